@@ -53,132 +53,54 @@ func (l *Loader) InstallModules(rootDir string, upgrade bool, hooks InstallHooks
 		Dir: rootDir,
 	}
 
-	_, cDiags := configs.BuildConfig(rootMod, configs.ModuleWalkerFunc(
-		func(req *configs.ModuleRequest) (*configs.Module, *version.Version, hcl.Diagnostics) {
-
-			key := manifestKey(req.Path)
-			instPath := l.packageInstallPath(req.Path)
-
-			// First we'll check if we need to upgrade/replace an existing
-			// installed module, and delete it out of the way if so.
-			replace := upgrade
-			if !replace {
-				record, recorded := l.modules.manifest[key]
-				switch {
-				case !recorded:
-					replace = true
-				case record.SourceAddr != req.SourceAddr:
-					replace = true
-				case record.Version != nil && !req.VersionConstraint.Required.Check(record.Version):
-					replace = true
-				}
-			}
-
-			// If we _are_ planning to replace this module, then we'll remove
-			// it now so our installation code below won't conflict with any
-			// existing remnants.
-			if replace {
-				delete(l.modules.manifest, key)
-				// Deleting a module invalidates all of its descendent modules too.
-				keyPrefix := key + "."
-				for subKey := range l.modules.manifest {
-					if strings.HasPrefix(subKey, keyPrefix) {
-						delete(l.modules.manifest, subKey)
-					}
-				}
-			}
+	// Remote modules (registry or go-getter) are installed in batches: each
+	// round below walks the currently-known configuration tree recording
+	// any not-yet-installed remote modules it encounters instead of
+	// installing them immediately, fetches that whole batch concurrently,
+	// and then repeats. Local modules are always resolved inline, in the
+	// same pass that discovers them, because their contents come from
+	// their parent package and require no fetch of their own. The loop
+	// ends once a pass discovers nothing new to fetch, which also means
+	// configs.BuildConfig was able to walk the whole tree without being
+	// blocked on a pending fetch.
+	for {
+		queue := &moduleInstallQueue{}
 
-			record, recorded := l.modules.manifest[key]
-			if !recorded {
-				// Clean up any stale cache directory that might be present.
-				err := l.modules.FS.RemoveAll(instPath)
-				if err != nil && !os.IsNotExist(err) {
-					diags = append(diags, &hcl.Diagnostic{
-						Severity: hcl.DiagError,
-						Summary:  "Failed to remove local module cache",
-						Detail: fmt.Sprintf(
-							"Terraform tried to remove %s in order to reinstall this module, but encountered an error: %s",
-							instPath, err,
-						),
-						Subject: &req.CallRange,
-					})
-					return nil, nil, diags
-				}
-			} else {
-				// If this module is already recorded and its root directory
-				// exists then we will just load what's already there and
-				// keep our existing record.
-				info, err := l.modules.FS.Stat(record.Dir)
-				if err == nil && info.IsDir() {
-					mod, mDiags := l.parser.LoadConfigDir(record.Dir)
-					diags = append(diags, mDiags...)
-					return mod, record.Version, diags
-				}
-			}
+		_, cDiags := configs.BuildConfig(rootMod, configs.ModuleWalkerFunc(
+			func(req *configs.ModuleRequest) (*configs.Module, *version.Version, hcl.Diagnostics) {
+				mod, ver, mDiags := l.installModuleRequestPhase1(req, upgrade, hooks, queue)
+				diags = append(diags, mDiags...)
+				return mod, ver, diags
+			},
+		))
+		diags = append(diags, cDiags...)
 
-			// If we get down here then it's finally time to actually install
-			// the module. There are some variants to this process depending
-			// on what type of module source address we have.
-			switch {
-
-			case isLocalSourceAddr(req.SourceAddr):
-				parentKey := manifestKey(req.Parent.Path)
-				parentRecord, recorded := l.modules.manifest[parentKey]
-				if !recorded {
-					// This is indicative of a bug rather than a user-actionable error
-					panic(fmt.Errorf("missing manifest record for parent module %s", parentKey))
-				}
-
-				if len(req.VersionConstraint.Required) != 0 {
-					diags = append(diags, &hcl.Diagnostic{
-						Severity: hcl.DiagError,
-						Summary:  "Invalid version constraint",
-						Detail:   "A version constraint cannot be applied to a module at a relative local path.",
-						Subject:  &req.VersionConstraint.DeclRange,
-					})
-				}
-
-				// For local sources we don't actually need to modify the
-				// filesystem at all because the parent already wrote
-				// the files we need, and so we just load up what's already here.
-				newDir := filepath.Join(parentRecord.Dir, req.SourceAddr)
-				mod, mDiags := l.parser.LoadConfigDir(newDir)
-				if mod == nil {
-					// nil indicates missing or unreadable directory, so we'll
-					// discard the returned diags and return a more specific
-					// error message here.
-					diags = append(diags, &hcl.Diagnostic{
-						Severity: hcl.DiagError,
-						Summary:  "Unreadable module directory",
-						Detail:   fmt.Sprintf("The directory %s could not be read.", newDir),
-						Subject:  &req.SourceAddrRange,
-					})
-				} else {
-					diags = append(diags, mDiags...)
-				}
-
-				// Note the local location in our manifest.
-				l.modules.manifest[key] = moduleRecord{
-					Key:        key,
-					Dir:        newDir,
-					SourceAddr: req.SourceAddr,
-				}
-				hooks.Install(key, nil, newDir)
-
-			case isRegistrySourceAddr(req.SourceAddr):
-				// TODO: Implement
-				panic("registry source installation not yet implemented")
-
-			default:
-				// TODO: Implement
-				panic("fallback source installation not yet implemented")
-
-			}
+		tasks := queue.drain()
+		if len(tasks) == 0 {
+			break
+		}
+		pDiags := l.installModulesParallel(tasks, hooks)
+		diags = append(diags, pDiags...)
+		if pDiags.HasErrors() {
+			break
+		}
+	}
 
-			return nil, nil, diags
-		},
-	))
-	diags = append(diags, cDiags...)
+	// Any non-local "module" blocks nested inside test file "run" blocks have their
+	// own dependencies installed alongside the root module's own, using a
+	// synthetic ModuleRequest per reference. installTestModuleDeps then
+	// recurses into each one's own module calls, so a test-referenced
+	// module's transitive dependencies are installed too, not just the
+	// module named directly in the "run" block.
+	for _, req := range l.testModuleRequests(rootDir) {
+		mod, _, mDiags := l.installModuleRequest(req, upgrade, hooks)
+		diags = append(diags, mDiags...)
+		if mod == nil {
+			continue
+		}
+		dir := l.modules.manifest[manifestKey(req.Path)].Dir
+		diags = append(diags, l.installTestModuleDeps(mod, dir, req.Path, upgrade, hooks)...)
+	}
 
 	err := l.modules.writeModuleManifestSnapshot()
 	if err != nil {
@@ -192,6 +114,198 @@ func (l *Loader) InstallModules(rootDir string, upgrade bool, hooks InstallHooks
 	return diags
 }
 
+// installModuleRequest installs a single module as requested by either the
+// ordinary configuration module walk or by testModuleRequests, sharing the
+// same local/registry/go-getter installation logic and manifest bookkeeping
+// in both cases.
+func (l *Loader) installModuleRequest(req *configs.ModuleRequest, upgrade bool, hooks InstallHooks) (*configs.Module, *version.Version, hcl.Diagnostics) {
+	key := manifestKey(req.Path)
+	instPath := l.packageInstallPath(req.Path)
+
+	mod, v, diags, resolved := l.precheckInstalled(req, key, instPath, upgrade)
+	if resolved {
+		return mod, v, diags
+	}
+
+	// If we get down here then it's finally time to actually install
+	// the module. There are some variants to this process depending
+	// on what type of module source address we have.
+	switch {
+
+	case isLocalSourceAddr(req.SourceAddr):
+		mod, lDiags := l.resolveLocalModule(req, key, hooks)
+		diags = append(diags, lDiags...)
+		return mod, nil, diags
+
+	case isRegistrySourceAddr(req.SourceAddr):
+		mod, ver, rDiags := l.installRegistryModule(req, key, instPath, hooks)
+		diags = append(diags, rDiags...)
+		return mod, ver, diags
+
+	default:
+		mod, fDiags := l.installFallbackModule(req, key, instPath, hooks)
+		diags = append(diags, fDiags...)
+		return mod, nil, diags
+
+	}
+}
+
+// precheckInstalled decides whether req's module is already installed and
+// up to date, clearing away any stale manifest record and cache directory
+// along the way if not. If the module can be resolved from what's already
+// on disk, resolved is true and mod/v/diags are the final result;
+// otherwise resolved is false and the caller should proceed to actually
+// install the module.
+func (l *Loader) precheckInstalled(req *configs.ModuleRequest, key, instPath string, upgrade bool) (mod *configs.Module, v *version.Version, diags hcl.Diagnostics, resolved bool) {
+	// First we'll check if we need to upgrade/replace an existing
+	// installed module, and delete it out of the way if so.
+	replace := upgrade
+	if !replace {
+		record, recorded := l.modules.manifest[key]
+		switch {
+		case !recorded:
+			replace = true
+		case record.SourceAddr != req.SourceAddr:
+			replace = true
+		case record.Version != nil && !req.VersionConstraint.Required.Check(record.Version):
+			replace = true
+		}
+	}
+
+	// If we _are_ planning to replace this module, then we'll remove
+	// it now so our installation code below won't conflict with any
+	// existing remnants.
+	if replace {
+		delete(l.modules.manifest, key)
+		// Deleting a module invalidates all of its descendent modules too.
+		keyPrefix := key + "."
+		for subKey := range l.modules.manifest {
+			if strings.HasPrefix(subKey, keyPrefix) {
+				delete(l.modules.manifest, subKey)
+			}
+		}
+	}
+
+	record, recorded := l.modules.manifest[key]
+	if !recorded {
+		// Clean up any stale cache directory that might be present.
+		err := l.modules.FS.RemoveAll(instPath)
+		if err != nil && !os.IsNotExist(err) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to remove local module cache",
+				Detail: fmt.Sprintf(
+					"Terraform tried to remove %s in order to reinstall this module, but encountered an error: %s",
+					instPath, err,
+				),
+				Subject: &req.CallRange,
+			})
+			return nil, nil, diags, true
+		}
+		return nil, nil, nil, false
+	}
+
+	// If this module is already recorded and its root directory exists
+	// then we will just load what's already there and keep our existing
+	// record. Modules fetched through the go-getter fallback have no
+	// other source of truth for "has this changed since we installed it",
+	// so for those we additionally reverify the on-disk package checksum
+	// we recorded at install time rather than trusting the directory's
+	// mere existence.
+	//
+	// This is the only place that checksum gets checked: it's shared by
+	// every caller of precheckInstalled, including the phase-1 walk, so a
+	// fallback module that's already installed and unmodified resolves
+	// here instead of being queued for re-fetch on every InstallModules
+	// pass.
+	if isLocalSourceAddr(req.SourceAddr) || isRegistrySourceAddr(req.SourceAddr) {
+		info, err := l.modules.FS.Stat(record.Dir)
+		if err == nil && info.IsDir() {
+			mod, mDiags := l.parser.LoadConfigDir(record.Dir)
+			diags = append(diags, mDiags...)
+			return mod, record.Version, diags, true
+		}
+		return nil, nil, nil, false
+	}
+
+	switch sum, err := hashPackageDir(instPath); {
+	case err != nil:
+		// Nothing on disk to verify (or it's unreadable); the caller
+		// will need to (re-)fetch it.
+	case sum == record.PackageSHA256:
+		mod, mDiags := l.parser.LoadConfigDir(record.Dir)
+		diags = append(diags, mDiags...)
+		return mod, record.Version, diags, true
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Module package checksum mismatch",
+			Detail: fmt.Sprintf(
+				"The package installed for %s no longer matches the checksum Terraform recorded when it was last installed (expected %s, got %s). Terraform will replace it.",
+				req.SourceAddr, record.PackageSHA256, sum,
+			),
+			Subject: &req.SourceAddrRange,
+		})
+		// Fall through and re-fetch the package.
+	}
+
+	return nil, nil, nil, false
+}
+
+// resolveLocalModule loads a module whose source address is a relative
+// local path, by reading it directly out of its parent module's already-
+// installed directory. Local modules never need to be fetched because
+// their contents were already written to disk as part of installing their
+// parent.
+func (l *Loader) resolveLocalModule(req *configs.ModuleRequest, key string, hooks InstallHooks) (*configs.Module, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	parentKey := manifestKey(req.Parent.Path)
+	parentRecord, recorded := l.modules.manifest[parentKey]
+	if !recorded {
+		// This is indicative of a bug rather than a user-actionable error
+		panic(fmt.Errorf("missing manifest record for parent module %s", parentKey))
+	}
+
+	if len(req.VersionConstraint.Required) != 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid version constraint",
+			Detail:   "A version constraint cannot be applied to a module at a relative local path.",
+			Subject:  &req.VersionConstraint.DeclRange,
+		})
+	}
+
+	// For local sources we don't actually need to modify the
+	// filesystem at all because the parent already wrote
+	// the files we need, and so we just load up what's already here.
+	newDir := filepath.Join(parentRecord.Dir, req.SourceAddr)
+	mod, mDiags := l.parser.LoadConfigDir(newDir)
+	if mod == nil {
+		// nil indicates missing or unreadable directory, so we'll
+		// discard the returned diags and return a more specific
+		// error message here.
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unreadable module directory",
+			Detail:   fmt.Sprintf("The directory %s could not be read.", newDir),
+			Subject:  &req.SourceAddrRange,
+		})
+	} else {
+		diags = append(diags, mDiags...)
+	}
+
+	// Note the local location in our manifest.
+	l.modules.manifest[key] = moduleRecord{
+		Key:        key,
+		Dir:        newDir,
+		SourceAddr: req.SourceAddr,
+	}
+	hooks.Install(key, nil, newDir)
+
+	return mod, diags
+}
+
 func (l *Loader) packageInstallPath(modulePath []string) string {
 	return filepath.Join(l.modules.Dir, strings.Join(modulePath, "."))
 }
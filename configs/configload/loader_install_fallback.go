@@ -0,0 +1,112 @@
+package configload
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/configs"
+)
+
+// packageFetcher returns the Loader's shared PackageFetcher, creating it
+// on first use.
+//
+// Safe for concurrent use: the phase-2 worker pool in InstallModules calls
+// this from multiple goroutines at once.
+func (l *Loader) packageFetcher() *PackageFetcher {
+	l.lazyInitMu.Lock()
+	defer l.lazyInitMu.Unlock()
+	if l.pkgFetcher == nil {
+		l.pkgFetcher = NewPackageFetcher()
+	}
+	return l.pkgFetcher
+}
+
+// installFallbackModule installs a module whose source address isn't a
+// local path or a module registry address, using go-getter to interpret it
+// as a git, hg, http, s3, gcs, or local file package address.
+//
+// Unlike registry modules, go-getter addresses have no notion of versions,
+// so a VersionConstraint on the request is rejected outright.
+//
+// Callers are expected to have already gone through precheckInstalled,
+// which is what decides whether an already-installed package can be
+// reused as-is (by its recorded checksum); installFallbackModule itself
+// always (re-)fetches.
+func (l *Loader) installFallbackModule(req *configs.ModuleRequest, key, instPath string, hooks InstallHooks) (*configs.Module, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	if cDiags := checkFallbackVersionConstraint(req); cDiags.HasErrors() {
+		return nil, cDiags
+	}
+
+	packageAddr, subDir := splitSubDir(req.SourceAddr)
+
+	hooks.Download(key, packageAddr, nil)
+
+	if err := l.packageFetcher().FetchPackage(instPath, packageAddr); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to install module",
+			Detail:   fmt.Sprintf("Error installing %s: %s", req.SourceAddr, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, diags
+	}
+
+	sum, err := hashPackageDir(instPath)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to verify installed module",
+			Detail:   fmt.Sprintf("Error computing a checksum for %s: %s", instPath, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, diags
+	}
+
+	finalDir := instPath
+	if subDir != "" {
+		finalDir = filepath.Join(instPath, subDir)
+	}
+
+	mod, mDiags := l.parser.LoadConfigDir(finalDir)
+	if mod == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unreadable module directory",
+			Detail:   fmt.Sprintf("The directory %s could not be read.", finalDir),
+			Subject:  &req.SourceAddrRange,
+		})
+	} else {
+		diags = append(diags, mDiags...)
+	}
+
+	l.modules.manifest[key] = moduleRecord{
+		Key:           key,
+		Dir:           finalDir,
+		SourceAddr:    req.SourceAddr,
+		PackageSHA256: sum,
+	}
+	hooks.Install(key, nil, finalDir)
+
+	return mod, diags
+}
+
+// checkFallbackVersionConstraint returns an error diagnostic if req carries
+// a version constraint. Go-getter addresses have no notion of versions, so
+// a constraint on one is always invalid; both installFallbackModule and the
+// parallel-install fetch path reject on this before attempting a fetch.
+func checkFallbackVersionConstraint(req *configs.ModuleRequest) hcl.Diagnostics {
+	if len(req.VersionConstraint.Required) == 0 {
+		return nil
+	}
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid version constraint",
+			Detail:   "A version constraint cannot be applied to a module sourced from a direct remote package address; only modules sourced from a registry support versions.",
+			Subject:  &req.VersionConstraint.DeclRange,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package configload
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/hcl2/hcl"
+)
+
+// TestLoaderInstallModules_fallbackChecksumDrift exercises the fix to
+// precheckInstalled/installFallbackModule: a go-getter-sourced module
+// that's modified on disk between InstallModules runs should be detected
+// via its recorded checksum, re-fetched, and reported with a diagnostic,
+// rather than silently trusted because its directory still exists.
+//
+// Test-referenced modules are used to drive this because they're
+// installed through installModuleRequest directly. See
+// TestLoaderInstallModules_parallelFanOutReuse for checksum-based reuse of
+// the root module's own unmodified go-getter modules, which go through the
+// phase-1/phase-2 loop instead; that test does not modify a package.
+func TestLoaderInstallModules_fallbackChecksumDrift(t *testing.T) {
+	fixtureDir := filepath.Clean("test-fixtures/fallback-drift")
+	tmpDir, err := ioutil.TempDir("", "configload-fallback-drift")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	modulesDir := filepath.Join(tmpDir, "modules")
+	if err := os.Mkdir(modulesDir, 0755); err != nil {
+		t.Fatalf("failed to create modules directory: %s", err)
+	}
+
+	loader, err := NewLoader(&Config{
+		ModulesDir: modulesDir,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from NewLoader: %s", err)
+	}
+
+	loader.pkgFetcher = &PackageFetcher{
+		Getters: map[string]getter.Getter{
+			"fake-getter": &fakeWriteGetter{
+				content: func(u *url.URL) string {
+					return `output "ok" { value = true }` + "\n"
+				},
+			},
+		},
+	}
+
+	hooks := &testInstallHooks{}
+	diags := loader.InstallModules(fixtureDir, false, hooks)
+	assertNoDiagnostics(t, diags)
+
+	key := manifestKey([]string{"tests", "uses-module.tftest.hcl", "main"})
+	record, ok := loader.modules.manifest[key]
+	if !ok {
+		t.Fatal("manifest is missing the test-referenced module")
+	}
+	if record.PackageSHA256 == "" {
+		t.Fatal("expected a package checksum to be recorded after install")
+	}
+	origSum := record.PackageSHA256
+
+	// Simulate the installed package being modified on disk, outside of
+	// Terraform's control, between install runs.
+	if err := ioutil.WriteFile(filepath.Join(record.Dir, "main.tf"), []byte(`output "ok" { value = false }`+"\n"), 0644); err != nil {
+		t.Fatalf("failed to modify installed module: %s", err)
+	}
+
+	diags = loader.InstallModules(fixtureDir, false, hooks)
+	if diags.HasErrors() {
+		t.Fatalf("unexpected errors on reinstall: %s", diags)
+	}
+
+	var found bool
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagWarning && diag.Summary == "Module package checksum mismatch" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a checksum mismatch warning after modifying the installed package")
+	}
+
+	newRecord, ok := loader.modules.manifest[key]
+	if !ok {
+		t.Fatal("manifest is missing the test-referenced module after the second install")
+	}
+	if newRecord.PackageSHA256 != origSum {
+		t.Errorf("expected the re-fetched package's checksum to match the original (%s), got %s", origSum, newRecord.PackageSHA256)
+	}
+}
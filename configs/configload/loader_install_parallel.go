@@ -0,0 +1,318 @@
+package configload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/configs"
+)
+
+// moduleInstallTask is a single remote module discovered during the
+// synchronous phase-1 walk in InstallModules, queued up to be fetched
+// during phase 2.
+type moduleInstallTask struct {
+	Req      *configs.ModuleRequest
+	Key      string
+	InstPath string
+}
+
+// moduleInstallQueue collects moduleInstallTasks discovered while walking
+// the configuration tree. It's written to from the (single-threaded)
+// configs.BuildConfig walker, so its only job is to preserve the order
+// tasks were discovered in for deterministic draining.
+type moduleInstallQueue struct {
+	mu    sync.Mutex
+	tasks []moduleInstallTask
+}
+
+func (q *moduleInstallQueue) enqueue(task moduleInstallTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = append(q.tasks, task)
+}
+
+func (q *moduleInstallQueue) drain() []moduleInstallTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := q.tasks
+	q.tasks = nil
+	return tasks
+}
+
+// maxParallelModuleInstalls returns the configured worker pool size for
+// phase-2 module fetches, defaulting to runtime.NumCPU() when the Loader
+// was not configured with an explicit MaxParallelModuleInstalls.
+func (l *Loader) maxParallelModuleInstalls() int {
+	if l.config != nil && l.config.MaxParallelModuleInstalls > 0 {
+		return l.config.MaxParallelModuleInstalls
+	}
+	return runtime.NumCPU()
+}
+
+// installModulesParallel is the phase-2 counterpart to the synchronous walk
+// in InstallModules: it fetches every queued remote module concurrently
+// into its own staging directory, then serially commits each one (in the
+// order it was queued) by renaming its staging directory into place,
+// updating the module manifest, and invoking the install hooks.
+//
+// The "no concurrent hook calls" contract documented on InstallModules is
+// preserved, and the manifest is only mutated from one goroutine at a
+// time, because only the fetch itself — which touches neither — runs
+// concurrently; the commit step is single-threaded.
+func (l *Loader) installModulesParallel(tasks []moduleInstallTask, hooks InstallHooks) hcl.Diagnostics {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	type fetchResult struct {
+		task       moduleInstallTask
+		stagingDir string
+		version    *version.Version
+		sha256     string // package checksum, for go-getter tasks only; empty for registry tasks
+		sourceAddr string // the resolved package address, for the Download hook
+		subDir     string // "//subdir" suffix from the original source address, if any
+		diags      hcl.Diagnostics
+	}
+
+	workers := l.maxParallelModuleInstalls()
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	taskCh := make(chan moduleInstallTask)
+	resultCh := make(chan fetchResult, len(tasks))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				stagingDir, sourceAddr, subDir, sum, v, diags := l.fetchModuleTask(task)
+				resultCh <- fetchResult{
+					task:       task,
+					stagingDir: stagingDir,
+					version:    v,
+					sha256:     sum,
+					sourceAddr: sourceAddr,
+					subDir:     subDir,
+					diags:      diags,
+				}
+			}
+		}()
+	}
+	go func() {
+		for _, task := range tasks {
+			taskCh <- task
+		}
+		close(taskCh)
+	}()
+	go func() {
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	results := make(map[string]fetchResult, len(tasks))
+	for result := range resultCh {
+		results[result.task.Key] = result
+	}
+
+	// Commit step: serialized, and in queue order, so that the resulting
+	// manifest and hook call sequence are deterministic regardless of
+	// which worker happened to finish fetching first.
+	var diags hcl.Diagnostics
+	for _, task := range tasks {
+		result := results[task.Key]
+		diags = append(diags, result.diags...)
+		if result.stagingDir == "" {
+			// Fetch failed; the task's diagnostics already explain why.
+			continue
+		}
+
+		hooks.Download(task.Key, result.sourceAddr, result.version)
+
+		if err := os.RemoveAll(task.InstPath); err != nil && !os.IsNotExist(err) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to install module",
+				Detail:   fmt.Sprintf("Error clearing the way for %s: %s", task.Req.SourceAddr, err),
+				Subject:  &task.Req.SourceAddrRange,
+			})
+			os.RemoveAll(result.stagingDir)
+			continue
+		}
+		if err := os.Rename(result.stagingDir, task.InstPath); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to install module",
+				Detail:   fmt.Sprintf("Error installing %s: %s", task.Req.SourceAddr, err),
+				Subject:  &task.Req.SourceAddrRange,
+			})
+			continue
+		}
+
+		finalDir := task.InstPath
+		if result.subDir != "" {
+			finalDir = filepath.Join(task.InstPath, result.subDir)
+		}
+
+		mod, mDiags := l.parser.LoadConfigDir(finalDir)
+		diags = append(diags, mDiags...)
+		if mod == nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unreadable module directory",
+				Detail:   fmt.Sprintf("The directory %s could not be read.", finalDir),
+				Subject:  &task.Req.SourceAddrRange,
+			})
+		}
+
+		l.modules.manifest[task.Key] = moduleRecord{
+			Key:           task.Key,
+			Dir:           finalDir,
+			SourceAddr:    task.Req.SourceAddr,
+			Version:       result.version,
+			PackageSHA256: result.sha256,
+		}
+		hooks.Install(task.Key, result.version, finalDir)
+	}
+
+	return diags
+}
+
+// fetchModuleTask resolves and fetches a single queued module into a new
+// staging directory, without touching any shared Loader state. It's safe
+// to call concurrently from multiple goroutines for different tasks.
+//
+// For go-getter tasks (anything that isn't a registry address) sum is the
+// package's content checksum, computed the same way installFallbackModule
+// computes it, so the parallel commit step can persist it to the manifest
+// just like the synchronous fallback path does. Registry tasks leave sum
+// empty, since they're versioned instead.
+func (l *Loader) fetchModuleTask(task moduleInstallTask) (stagingDir, packageAddr, subDir, sum string, v *version.Version, diags hcl.Diagnostics) {
+	req := task.Req
+
+	staging, err := ioutil.TempDir(l.modules.Dir, ".terraform-get-")
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to install module",
+			Detail:   fmt.Sprintf("Error creating a staging directory for %s: %s", req.SourceAddr, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return "", "", "", "", nil, diags
+	}
+
+	if isRegistrySourceAddr(req.SourceAddr) {
+		addr, sd, err := parseRegistryAddr(req.SourceAddr)
+		subDir = sd
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid module registry address",
+				Detail:   err.Error(),
+				Subject:  &req.SourceAddrRange,
+			})
+			os.RemoveAll(staging)
+			return "", "", "", "", nil, diags
+		}
+
+		client := l.registryClient()
+		v, err = client.NewestMatchingVersion(addr, req.VersionConstraint.Required)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to resolve module version",
+				Detail:   fmt.Sprintf("Could not resolve a version of %s: %s", addr, err),
+				Subject:  &req.SourceAddrRange,
+			})
+			os.RemoveAll(staging)
+			return "", "", "", "", nil, diags
+		}
+
+		packageAddr, err = client.Location(addr, v)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to locate module package",
+				Detail:   fmt.Sprintf("Could not determine a download location for %s %s: %s", addr, v, err),
+				Subject:  &req.SourceAddrRange,
+			})
+			os.RemoveAll(staging)
+			return "", "", "", "", nil, diags
+		}
+	} else {
+		if cDiags := checkFallbackVersionConstraint(req); cDiags.HasErrors() {
+			diags = append(diags, cDiags...)
+			os.RemoveAll(staging)
+			return "", "", "", "", nil, diags
+		}
+		packageAddr, subDir = splitSubDir(req.SourceAddr)
+	}
+
+	if err := l.packageFetcher().Stage(staging, packageAddr); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to install module",
+			Detail:   fmt.Sprintf("Error installing %s: %s", req.SourceAddr, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		os.RemoveAll(staging)
+		return "", "", "", "", nil, diags
+	}
+
+	if !isRegistrySourceAddr(req.SourceAddr) {
+		sum, err = hashPackageDir(staging)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to verify installed module",
+				Detail:   fmt.Sprintf("Error computing a checksum for %s: %s", staging, err),
+				Subject:  &req.SourceAddrRange,
+			})
+			os.RemoveAll(staging)
+			return "", "", "", "", nil, diags
+		}
+	}
+
+	return staging, packageAddr, subDir, sum, v, diags
+}
+
+// installModuleRequestPhase1 is the phase-1 ModuleWalkerFunc body used by
+// InstallModules. Local modules are resolved inline, exactly as
+// installModuleRequest would; registry and go-getter modules that aren't
+// already installed are instead queued for phase 2 and reported back to
+// configs.BuildConfig as having no content, so that BuildConfig simply
+// won't descend into them this round.
+func (l *Loader) installModuleRequestPhase1(req *configs.ModuleRequest, upgrade bool, hooks InstallHooks, queue *moduleInstallQueue) (*configs.Module, *version.Version, hcl.Diagnostics) {
+	key := manifestKey(req.Path)
+	instPath := l.packageInstallPath(req.Path)
+
+	mod, v, diags, resolved := l.precheckInstalled(req, key, instPath, upgrade)
+	if resolved {
+		return mod, v, diags
+	}
+
+	if isLocalSourceAddr(req.SourceAddr) {
+		mod, lDiags := l.resolveLocalModule(req, key, hooks)
+		diags = append(diags, lDiags...)
+		return mod, nil, diags
+	}
+
+	// Registry and go-getter modules are deferred to phase 2: we don't yet
+	// know their content, so BuildConfig won't be able to discover any of
+	// their own nested module calls until a later round, once this one has
+	// been fetched and committed to the manifest.
+	queue.enqueue(moduleInstallTask{
+		Req:      req,
+		Key:      key,
+		InstPath: instPath,
+	})
+	return nil, nil, diags
+}
@@ -0,0 +1,157 @@
+package configload
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/configs"
+	"github.com/hashicorp/terraform/configs/configload/registry"
+)
+
+// defaultRegistryHost is the hostname assumed for a registry module source
+// address that doesn't specify one explicitly, e.g. "hashicorp/consul/aws".
+const defaultRegistryHost = "registry.terraform.io"
+
+// registryClient returns the Loader's shared registry client, creating it
+// on first use. Reusing a single client across all modules in a config
+// lets its per-host discovery and version caches do their job: a config
+// with many modules from the same registry host only costs one discovery
+// round-trip.
+//
+// Safe for concurrent use: the phase-2 worker pool in InstallModules calls
+// this from multiple goroutines at once.
+func (l *Loader) registryClient() *registry.Client {
+	l.lazyInitMu.Lock()
+	defer l.lazyInitMu.Unlock()
+	if l.regClient == nil {
+		l.regClient = registry.NewClient(nil)
+	}
+	return l.regClient
+}
+
+// installRegistryModule installs a module addressed through the Terraform
+// module registry protocol: it resolves the best matching version, asks
+// the registry where to fetch the package from, and extracts it into the
+// module's install path.
+func (l *Loader) installRegistryModule(req *configs.ModuleRequest, key, instPath string, hooks InstallHooks) (*configs.Module, *version.Version, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	addr, subDir, err := parseRegistryAddr(req.SourceAddr)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid module registry address",
+			Detail:   err.Error(),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, nil, diags
+	}
+
+	client := l.registryClient()
+
+	v, err := client.NewestMatchingVersion(addr, req.VersionConstraint.Required)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to resolve module version",
+			Detail:   fmt.Sprintf("Could not resolve a version of %s: %s", addr, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, nil, diags
+	}
+
+	packageAddr, err := client.Location(addr, v)
+	if err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to locate module package",
+			Detail:   fmt.Sprintf("Could not determine a download location for %s %s: %s", addr, v, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, nil, diags
+	}
+
+	hooks.Download(key, packageAddr, v)
+
+	if err := l.packageFetcher().FetchPackage(instPath, packageAddr); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to install module",
+			Detail:   fmt.Sprintf("Error installing %s %s: %s", addr, v, err),
+			Subject:  &req.SourceAddrRange,
+		})
+		return nil, nil, diags
+	}
+
+	finalDir := instPath
+	if subDir != "" {
+		finalDir = filepath.Join(instPath, subDir)
+	}
+
+	mod, mDiags := l.parser.LoadConfigDir(finalDir)
+	if mod == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unreadable module directory",
+			Detail:   fmt.Sprintf("The directory %s could not be read.", finalDir),
+			Subject:  &req.SourceAddrRange,
+		})
+	} else {
+		diags = append(diags, mDiags...)
+	}
+
+	l.modules.manifest[key] = moduleRecord{
+		Key:        key,
+		Dir:        finalDir,
+		SourceAddr: req.SourceAddr,
+		Version:    v,
+	}
+	hooks.Install(key, v, finalDir)
+
+	return mod, v, diags
+}
+
+// parseRegistryAddr splits a registry module source address into the
+// registry-protocol address it identifies and any "//subdir" suffix.
+func parseRegistryAddr(sourceAddr string) (registry.ModuleAddr, string, error) {
+	given, subDir := splitSubDir(sourceAddr)
+
+	parts := strings.Split(given, "/")
+	var host string
+	switch len(parts) {
+	case 3:
+		host = defaultRegistryHost
+	case 4:
+		host = parts[0]
+		parts = parts[1:]
+	default:
+		return registry.ModuleAddr{}, "", fmt.Errorf("module address %q is not a valid registry source", sourceAddr)
+	}
+
+	return registry.ModuleAddr{
+		Host:      host,
+		Namespace: parts[0],
+		Name:      parts[1],
+		Provider:  parts[2],
+	}, subDir, nil
+}
+
+// splitSubDir splits a "//subdir" suffix off of a module source address, if
+// present, returning the package address and the subdirectory path
+// separately.
+func splitSubDir(sourceAddr string) (string, string) {
+	// Skip past any "scheme://" prefix so that its slashes aren't mistaken
+	// for the subdirectory separator.
+	offset := 0
+	if idx := strings.Index(sourceAddr, "://"); idx >= 0 {
+		offset = idx + 3
+	}
+	if idx := strings.Index(sourceAddr[offset:], "//"); idx >= 0 {
+		idx += offset
+		return sourceAddr[:idx], sourceAddr[idx+2:]
+	}
+	return sourceAddr, ""
+}
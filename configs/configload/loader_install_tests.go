@@ -0,0 +1,108 @@
+package configload
+
+import (
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/configs"
+	"github.com/spf13/afero"
+)
+
+// testModuleRequests scans rootDir for test suite files and returns a
+// synthetic ModuleRequest for every "module" block nested inside a "run"
+// block whose source is a non-local address, so that InstallModules can
+// install their dependencies alongside the root module's own. Blocks with a
+// local source, or with a source that isn't a known string, are skipped.
+//
+// Test files are re-parsed here rather than reusing a prior
+// LoadConfigWithTests result because InstallModules can be called on its
+// own, ahead of any call to LoadConfigWithTests.
+func (l *Loader) testModuleRequests(rootDir string) []*configs.ModuleRequest {
+	var reqs []*configs.ModuleRequest
+
+	var paths []string
+	for _, glob := range testFileGlobs {
+		matches, err := afero.Glob(l.modules.FS, filepath.Join(rootDir, glob))
+		if err != nil {
+			continue
+		}
+		paths = append(paths, matches...)
+	}
+
+	for _, path := range paths {
+		tf, _ := l.parser.LoadTestFile(path)
+		if tf == nil {
+			continue
+		}
+		for _, run := range tf.Runs {
+			if run.Module == nil {
+				continue
+			}
+			sourceVal, diags := run.Module.Source.Value(nil)
+			if diags.HasErrors() || sourceVal.IsNull() {
+				continue
+			}
+			sourceAddr := sourceVal.AsString()
+			if isLocalSourceAddr(sourceAddr) {
+				// Local test fixture modules are read directly from disk
+				// when the test runs rather than through InstallModules.
+				continue
+			}
+
+			reqs = append(reqs, &configs.ModuleRequest{
+				Path:       []string{"tests", filepath.Base(path), run.Name},
+				SourceAddr: sourceAddr,
+				CallRange:  run.Module.DeclRange,
+			})
+		}
+	}
+
+	return reqs
+}
+
+// installTestModuleDeps installs the dependencies of mod, which was just
+// installed at dir under the manifest key path basePath. It's the
+// recursive counterpart to testModuleRequests: a test-referenced module
+// can itself declare "module" calls of its own, and those need installing
+// too, or they'll be silently missing when the test run actually executes.
+//
+// Local nested module calls are never fetched — like resolveLocalModule,
+// we just read them directly out of the directory their parent was
+// already installed into — but we still recurse into them, since a local
+// nested module can in turn declare a remote call of its own.
+func (l *Loader) installTestModuleDeps(mod *configs.Module, dir string, basePath []string, upgrade bool, hooks InstallHooks) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for name, call := range mod.ModuleCalls {
+		path := append(append([]string{}, basePath...), name)
+
+		if isLocalSourceAddr(call.SourceAddr) {
+			childDir := filepath.Join(dir, call.SourceAddr)
+			childMod, mDiags := l.parser.LoadConfigDir(childDir)
+			diags = append(diags, mDiags...)
+			if childMod == nil {
+				continue
+			}
+			diags = append(diags, l.installTestModuleDeps(childMod, childDir, path, upgrade, hooks)...)
+			continue
+		}
+
+		req := &configs.ModuleRequest{
+			Path:              path,
+			SourceAddr:        call.SourceAddr,
+			SourceAddrRange:   call.SourceAddrRange,
+			VersionConstraint: call.Version,
+			CallRange:         call.DeclRange,
+		}
+		childMod, _, mDiags := l.installModuleRequest(req, upgrade, hooks)
+		diags = append(diags, mDiags...)
+		if childMod == nil {
+			continue
+		}
+
+		childDir := l.modules.manifest[manifestKey(path)].Dir
+		diags = append(diags, l.installTestModuleDeps(childMod, childDir, path, upgrade, hooks)...)
+	}
+
+	return diags
+}
@@ -0,0 +1,91 @@
+package configload
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	getter "github.com/hashicorp/go-getter"
+)
+
+// fakeWriteGetter "fetches" a module by writing a single main.tf file whose
+// content depends on the address being fetched, simulating a go-getter
+// package without any network access.
+type fakeWriteGetter struct {
+	content func(u *url.URL) string
+}
+
+func (g *fakeWriteGetter) Get(dst string, u *url.URL) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dst, "main.tf"), []byte(g.content(u)), 0644)
+}
+
+func (g *fakeWriteGetter) GetFile(dst string, u *url.URL) error {
+	return ioutil.WriteFile(dst, nil, 0644)
+}
+
+func (g *fakeWriteGetter) ClientMode(u *url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+// TestLoaderInstallModules_testModuleDeps verifies the fix to
+// testModuleRequests/installTestModuleDeps: a module referenced from a
+// test file's "run" block has its own nested module calls installed too,
+// not just the module named directly in the override.
+func TestLoaderInstallModules_testModuleDeps(t *testing.T) {
+	fixtureDir := filepath.Clean("test-fixtures/test-module-deps")
+	tmpDir, err := ioutil.TempDir("", "configload-test-deps")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	modulesDir := filepath.Join(tmpDir, "modules")
+	if err := os.Mkdir(modulesDir, 0755); err != nil {
+		t.Fatalf("failed to create modules directory: %s", err)
+	}
+
+	loader, err := NewLoader(&Config{
+		ModulesDir: modulesDir,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from NewLoader: %s", err)
+	}
+
+	loader.pkgFetcher = &PackageFetcher{
+		Getters: map[string]getter.Getter{
+			"fake-getter": &fakeWriteGetter{
+				content: func(u *url.URL) string {
+					if strings.Contains(u.Path, "leaf") {
+						// The leaf module has no further module calls of
+						// its own, ending the recursion.
+						return `output "ok" { value = true }` + "\n"
+					}
+					return `
+module "nested" {
+  source = "fake-getter://example.com/leaf"
+}
+`
+				},
+			},
+		},
+	}
+
+	hooks := &testInstallHooks{}
+	diags := loader.InstallModules(fixtureDir, false, hooks)
+	assertNoDiagnostics(t, diags)
+
+	topKey := manifestKey([]string{"tests", "uses-module.tftest.hcl", "main"})
+	if _, ok := loader.modules.manifest[topKey]; !ok {
+		t.Fatalf("manifest is missing the test-referenced module")
+	}
+
+	nestedKey := manifestKey([]string{"tests", "uses-module.tftest.hcl", "main", "nested"})
+	if _, ok := loader.modules.manifest[nestedKey]; !ok {
+		t.Fatalf("manifest is missing the test-referenced module's own nested module call")
+	}
+}
@@ -0,0 +1,61 @@
+package configload
+
+import (
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/configs"
+	"github.com/spf13/afero"
+)
+
+// testFileGlobs are the filename patterns that identify a test suite file,
+// in the order they should be searched for within a given directory.
+var testFileGlobs = []string{"*.tftest.hcl", "*.tftest.json"}
+
+// LoadConfigWithTests is a variant of LoadConfig which also discovers and
+// parses any test suite files (.tftest.hcl or .tftest.json) found in the
+// root module directory and, if given, in an additional external testDir,
+// attaching the result to the returned config's Tests field.
+//
+// testDir may be empty, in which case only test files alongside the root
+// module are considered. testDir is typically used for a repository-wide
+// tests/ directory that is shared across several root modules.
+func (l *Loader) LoadConfigWithTests(rootDir, testDir string) (*configs.Config, hcl.Diagnostics) {
+	config, diags := l.LoadConfig(rootDir)
+	if config == nil {
+		return nil, diags
+	}
+
+	tests := map[string]*configs.TestFile{}
+
+	dirs := []string{rootDir}
+	if testDir != "" {
+		dirs = append(dirs, testDir)
+	}
+	for _, dir := range dirs {
+		var paths []string
+		for _, glob := range testFileGlobs {
+			matches, err := afero.Glob(l.modules.FS, filepath.Join(dir, glob))
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Failed to read test directory",
+					Detail:   err.Error(),
+				})
+				continue
+			}
+			paths = append(paths, matches...)
+		}
+
+		for _, path := range paths {
+			tf, tDiags := l.parser.LoadTestFile(path)
+			diags = append(diags, tDiags...)
+			if tf != nil {
+				tests[filepath.Base(path)] = tf
+			}
+		}
+	}
+
+	config.Tests = tests
+	return config, diags
+}
@@ -0,0 +1,34 @@
+package configload
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoaderLoadConfigWithTests(t *testing.T) {
+	fixtureDir := filepath.Clean("test-fixtures/with-tests")
+	loader, err := NewLoader(&Config{
+		ModulesDir: filepath.Join(fixtureDir, ".terraform/modules"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from NewLoader: %s", err)
+	}
+
+	config, diags := loader.LoadConfigWithTests(fixtureDir, "")
+	assertNoDiagnostics(t, diags)
+
+	if got, want := len(config.Tests), 1; got != want {
+		t.Fatalf("wrong number of test files: got %d, want %d", got, want)
+	}
+
+	tf, ok := config.Tests["basic.tftest.hcl"]
+	if !ok {
+		t.Fatal("missing test file \"basic.tftest.hcl\"")
+	}
+	if got, want := len(tf.Runs), 1; got != want {
+		t.Fatalf("wrong number of runs: got %d, want %d", got, want)
+	}
+	if got, want := tf.Runs[0].Name, "main"; got != want {
+		t.Errorf("wrong run name: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package configload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// hashPackageDir computes a SHA256 digest over the contents and relative
+// paths of every regular file in dir, providing a lightweight integrity
+// check for a fetched module package between InstallModules runs.
+//
+// It is not intended as a cryptographic attestation of a package's
+// contents (there is nothing yet to compare it against except a value we
+// ourselves previously recorded) but it is enough to detect a package
+// that has been modified, corrupted, or replaced on disk outside of
+// Terraform's control.
+func hashPackageDir(dir string) (string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to walk %s: %s", dir, err)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, rel := range paths {
+		f, err := os.Open(filepath.Join(dir, rel))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %s", rel, err)
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %s", rel, err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,78 @@
+package configload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestPackageDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "configload-checksum")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	for rel, content := range files {
+		full := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %s", rel, err)
+		}
+		if err := ioutil.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", rel, err)
+		}
+	}
+	return dir
+}
+
+func TestHashPackageDir(t *testing.T) {
+	dirA := writeTestPackageDir(t, map[string]string{
+		"main.tf":          "resource \"test\" \"a\" {}\n",
+		"nested/README.md": "hello\n",
+	})
+	defer os.RemoveAll(dirA)
+
+	sumA, err := hashPackageDir(dirA)
+	if err != nil {
+		t.Fatalf("unexpected error from hashPackageDir: %s", err)
+	}
+	if sumA == "" {
+		t.Fatal("expected a non-empty checksum")
+	}
+
+	// Hashing the same content again, in a different directory, must
+	// produce the same checksum: the hash is over relative paths and
+	// content, not the absolute directory.
+	dirB := writeTestPackageDir(t, map[string]string{
+		"main.tf":          "resource \"test\" \"a\" {}\n",
+		"nested/README.md": "hello\n",
+	})
+	defer os.RemoveAll(dirB)
+
+	sumB, err := hashPackageDir(dirB)
+	if err != nil {
+		t.Fatalf("unexpected error from hashPackageDir: %s", err)
+	}
+	if sumA != sumB {
+		t.Errorf("checksums for identical content differ: %s vs %s", sumA, sumB)
+	}
+
+	// Changing a file's content must change the checksum.
+	if err := ioutil.WriteFile(filepath.Join(dirB, "main.tf"), []byte("resource \"test\" \"b\" {}\n"), 0644); err != nil {
+		t.Fatalf("failed to modify main.tf: %s", err)
+	}
+	sumC, err := hashPackageDir(dirB)
+	if err != nil {
+		t.Fatalf("unexpected error from hashPackageDir: %s", err)
+	}
+	if sumA == sumC {
+		t.Error("checksum did not change after modifying a file's content")
+	}
+}
+
+func TestHashPackageDir_missing(t *testing.T) {
+	if _, err := hashPackageDir(filepath.Join(os.TempDir(), "configload-checksum-does-not-exist")); err == nil {
+		t.Fatal("expected an error for a directory that doesn't exist")
+	}
+}
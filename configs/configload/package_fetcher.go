@@ -0,0 +1,77 @@
+package configload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	getter "github.com/hashicorp/go-getter"
+)
+
+// PackageFetcher knows how to fetch and extract a module package from any
+// source address supported by go-getter (git, hg, http, s3, gcs, local
+// file, etc). It exists mainly so that tests can inject a fake Detectors/
+// Getters map in place of the real network-backed ones.
+type PackageFetcher struct {
+	// Detectors and Getters are passed through to the underlying
+	// getter.Client. A nil value for either means "use go-getter's
+	// built-in defaults".
+	Detectors []getter.Detector
+	Getters   map[string]getter.Getter
+}
+
+// NewPackageFetcher returns a PackageFetcher configured with go-getter's
+// standard detectors and getters.
+func NewPackageFetcher() *PackageFetcher {
+	return &PackageFetcher{
+		Detectors: getter.Detectors,
+		Getters:   getter.Getters,
+	}
+}
+
+// FetchPackage fetches the package at src and extracts it into dst.
+//
+// The extraction happens into a temporary staging directory alongside dst
+// first, and is only renamed into place once it has completed
+// successfully, so that a failed or interrupted fetch can never leave a
+// partially-extracted package at dst.
+func (f *PackageFetcher) FetchPackage(dst, src string) error {
+	stagingDir, err := ioutil.TempDir(filepath.Dir(dst), ".terraform-get-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory for fetch: %s", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := f.Stage(stagingDir, src); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dst); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove previous module directory: %s", err)
+	}
+	if err := os.Rename(stagingDir, dst); err != nil {
+		return fmt.Errorf("failed to install fetched package: %s", err)
+	}
+
+	return nil
+}
+
+// Stage fetches the package at src and extracts it into stagingDir, which
+// must already exist and be empty. Unlike FetchPackage it does not move
+// the result anywhere, which makes it safe to call concurrently for
+// distinct staging directories: callers that want the atomic-rename
+// behavior of FetchPackage but need to perform the fetch itself in
+// parallel with other fetches can call Stage and commit the result
+// themselves once all concurrent fetches have completed.
+func (f *PackageFetcher) Stage(stagingDir, src string) error {
+	client := &getter.Client{
+		Src:       src,
+		Dst:       stagingDir,
+		Pwd:       stagingDir,
+		Mode:      getter.ClientModeDir,
+		Detectors: f.Detectors,
+		Getters:   f.Getters,
+	}
+	return client.Get()
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"net/http"
+	"sync"
+)
+
+// newAuthenticatedRequest builds a request for url with the given method, attaching a Bearer
+// token as configured for host in the user's CLI credentials, if any.
+func (c *Client) newAuthenticatedRequest(method, url, host string) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := tokenForHost(host)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// Client is a client for the Terraform module registry protocol. A Client
+// caches discovery documents and version lists per host, so it's best to
+// reuse a single Client across all of the modules installed for a given
+// configuration rather than constructing a new one per module.
+//
+// Client is safe for concurrent use by multiple goroutines.
+type Client struct {
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	discoCache map[string]*Discovery
+	verCache   map[ModuleAddr][]string
+}
+
+// ModuleAddr identifies a module within a particular registry host, in the
+// form used by module source addresses such as
+// "registry.example.com/hashicorp/consul/aws".
+type ModuleAddr struct {
+	Host      string
+	Namespace string
+	Name      string
+	Provider  string
+}
+
+// NewClient returns a new registry Client ready for use. httpClient may be
+// nil, in which case http.DefaultClient is used.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient: httpClient,
+		discoCache: map[string]*Discovery{},
+		verCache:   map[ModuleAddr][]string{},
+	}
+}
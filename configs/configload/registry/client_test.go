@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// newTestServer starts an HTTPS test server implementing the discovery,
+// versions, and download endpoints of the modules.v1 protocol for a single
+// module, and returns a Client that trusts its certificate along with the
+// server itself; callers set addr.Host from the server's URL. modulesV1 is the raw "modules.v1" value advertised by
+// the discovery document, letting callers exercise non-conformant values
+// (e.g. missing the trailing slash the protocol requires).
+func newTestServer(t *testing.T, addr ModuleAddr, modulesV1 string, versions []string, downloadLocation string) (*Client, *httptest.Server) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"modules.v1":%q}`, modulesV1)
+	})
+
+	versionsPath := fmt.Sprintf("/v1/modules/%s/%s/%s/versions", addr.Namespace, addr.Name, addr.Provider)
+	mux.HandleFunc(versionsPath, func(w http.ResponseWriter, r *http.Request) {
+		var vs []string
+		for _, v := range versions {
+			vs = append(vs, fmt.Sprintf(`{"version":%q}`, v))
+		}
+		fmt.Fprintf(w, `{"modules":[{"versions":[%s]}]}`, strings.Join(vs, ","))
+	})
+
+	downloadPathPrefix := fmt.Sprintf("/v1/modules/%s/%s/%s/", addr.Namespace, addr.Name, addr.Provider)
+	mux.HandleFunc(downloadPathPrefix, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/download") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Terraform-Get", downloadLocation)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewTLSServer(mux)
+	client := NewClient(server.Client())
+	return client, server
+}
+
+func TestClientVersionsAndLocation(t *testing.T) {
+	addr := ModuleAddr{Namespace: "hashicorp", Name: "consul", Provider: "aws"}
+	client, server := newTestServer(t, addr, "/v1/modules/", []string{"0.1.0", "0.2.0", "1.0.0"}, "https://example.com/packages/consul-aws-1.0.0.tar.gz")
+	defer server.Close()
+	addr.Host = strings.TrimPrefix(server.URL, "https://")
+
+	versions, err := client.Versions(addr)
+	if err != nil {
+		t.Fatalf("unexpected error from Versions: %s", err)
+	}
+	if got, want := len(versions), 3; got != want {
+		t.Fatalf("wrong number of versions: got %d, want %d", got, want)
+	}
+
+	v, err := client.NewestMatchingVersion(addr, version.Constraints{})
+	if err != nil {
+		t.Fatalf("unexpected error from NewestMatchingVersion: %s", err)
+	}
+	if got, want := v.String(), "1.0.0"; got != want {
+		t.Errorf("wrong newest version: got %q, want %q", got, want)
+	}
+
+	loc, err := client.Location(addr, v)
+	if err != nil {
+		t.Fatalf("unexpected error from Location: %s", err)
+	}
+	if got, want := loc, "https://example.com/packages/consul-aws-1.0.0.tar.gz"; got != want {
+		t.Errorf("wrong download location: got %q, want %q", got, want)
+	}
+}
+
+func TestClientVersionsAndLocation_discoveryMissingTrailingSlash(t *testing.T) {
+	addr := ModuleAddr{Namespace: "hashicorp", Name: "consul", Provider: "aws"}
+	// The modules.v1 protocol requires this value to end in a slash, but
+	// discover() must tolerate a host that forgets it rather than build a
+	// malformed URL for every subsequent request.
+	client, server := newTestServer(t, addr, "/v1/modules", []string{"1.0.0"}, "https://example.com/packages/consul-aws-1.0.0.tar.gz")
+	defer server.Close()
+	addr.Host = strings.TrimPrefix(server.URL, "https://")
+
+	v, err := client.NewestMatchingVersion(addr, version.Constraints{})
+	if err != nil {
+		t.Fatalf("unexpected error from NewestMatchingVersion: %s", err)
+	}
+
+	loc, err := client.Location(addr, v)
+	if err != nil {
+		t.Fatalf("unexpected error from Location: %s", err)
+	}
+	if got, want := loc, "https://example.com/packages/consul-aws-1.0.0.tar.gz"; got != want {
+		t.Errorf("wrong download location: got %q, want %q", got, want)
+	}
+}
+
+func TestClientNewestMatchingVersion_noMatch(t *testing.T) {
+	addr := ModuleAddr{Namespace: "hashicorp", Name: "consul", Provider: "aws"}
+	client, server := newTestServer(t, addr, "/v1/modules/", []string{"0.1.0"}, "")
+	defer server.Close()
+	addr.Host = strings.TrimPrefix(server.URL, "https://")
+
+	constraint, err := version.NewConstraint(">= 1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error parsing constraint: %s", err)
+	}
+
+	if _, err := client.NewestMatchingVersion(addr, constraint); err == nil {
+		t.Fatal("expected an error when no published version satisfies the constraint")
+	}
+}
+
+func TestClientVersions_cached(t *testing.T) {
+	addr := ModuleAddr{Namespace: "hashicorp", Name: "consul", Provider: "aws"}
+
+	var requestCount int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"modules.v1":"/v1/modules/"}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/v1/modules/%s/%s/%s/versions", addr.Namespace, addr.Name, addr.Provider), func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		fmt.Fprint(w, `{"modules":[{"versions":[{"version":"1.0.0"}]}]}`)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	addr.Host = strings.TrimPrefix(server.URL, "https://")
+
+	client := NewClient(server.Client())
+	if _, err := client.Versions(addr); err != nil {
+		t.Fatalf("unexpected error from Versions: %s", err)
+	}
+	if _, err := client.Versions(addr); err != nil {
+		t.Fatalf("unexpected error from Versions: %s", err)
+	}
+
+	if got, want := requestCount, 1; got != want {
+		t.Fatalf("wrong number of versions requests: got %d, want %d (results should be cached)", got, want)
+	}
+}
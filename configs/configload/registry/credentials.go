@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hclparse"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// credentialsFile is the decoded shape of the "credentials" blocks in a
+// user's CLI config file (~/.terraformrc, see cliConfigFilePath). Remain
+// captures the file's other top-level settings (e.g. plugin_cache_dir),
+// which we don't care about here but which gohcl would otherwise reject
+// as unrecognized.
+type credentialsFile struct {
+	Credentials []struct {
+		Host  string `hcl:"host,label"`
+		Token string `hcl:"token,optional"`
+	} `hcl:"credentials,block"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// tokenForHost returns the API token configured for the given registry
+// hostname in the user's CLI configuration file, or an empty string if
+// none is configured. A missing or unreadable CLI config file is not
+// treated as an error: it simply means no token is available.
+func tokenForHost(host string) (string, error) {
+	path, err := cliConfigFilePath()
+	if err != nil || path == "" {
+		return "", nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+
+	parser := hclparse.NewParser()
+	f, diags := parser.ParseHCLFile(path)
+	if f == nil {
+		return "", fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+
+	var creds credentialsFile
+	if diags := gohcl.DecodeBody(f.Body, nil, &creds); diags.HasErrors() {
+		return "", fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+
+	for _, block := range creds.Credentials {
+		if block.Host == host {
+			return block.Token, nil
+		}
+	}
+	return "", nil
+}
+
+// cliConfigFilePath returns the path to the user's CLI configuration file,
+// following the same ~/.terraformrc convention used by the rest of
+// Terraform's CLI config loading.
+func cliConfigFilePath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".terraformrc"), nil
+}
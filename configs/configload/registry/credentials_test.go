@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// withTestHome points $HOME (and, on Windows, %USERPROFILE%) at a fresh
+// temporary directory, so cliConfigFilePath resolves to a file we control
+// rather than the real user's. The caller must invoke the returned restore
+// function (typically via defer) once the test is done with it.
+func withTestHome(t *testing.T) (dir string, restore func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "registry-credentials")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+
+	type savedEnv struct {
+		name string
+		val  string
+		had  bool
+	}
+	var saved []savedEnv
+	for _, envVar := range []string{"HOME", "USERPROFILE"} {
+		old, had := os.LookupEnv(envVar)
+		saved = append(saved, savedEnv{envVar, old, had})
+		os.Setenv(envVar, dir)
+	}
+	// homedir caches the first home directory it finds, so clear that
+	// cache for the new environment to take effect.
+	homedir.Reset()
+
+	return dir, func() {
+		os.RemoveAll(dir)
+		for _, s := range saved {
+			if s.had {
+				os.Setenv(s.name, s.val)
+			} else {
+				os.Unsetenv(s.name)
+			}
+		}
+		homedir.Reset()
+	}
+}
+
+func TestTokenForHost(t *testing.T) {
+	home, restore := withTestHome(t)
+	defer restore()
+	rc := `
+credentials "example.com" {
+  token = "abc123"
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(home, ".terraformrc"), []byte(rc), 0600); err != nil {
+		t.Fatalf("failed to write .terraformrc: %s", err)
+	}
+
+	token, err := tokenForHost("example.com")
+	if err != nil {
+		t.Fatalf("unexpected error from tokenForHost: %s", err)
+	}
+	if got, want := token, "abc123"; got != want {
+		t.Errorf("wrong token: got %q, want %q", got, want)
+	}
+
+	token, err = tokenForHost("other.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error from tokenForHost: %s", err)
+	}
+	if token != "" {
+		t.Errorf("expected no token for an unconfigured host, got %q", token)
+	}
+}
+
+func TestTokenForHost_noConfigFile(t *testing.T) {
+	_, restore := withTestHome(t)
+	defer restore()
+
+	token, err := tokenForHost("example.com")
+	if err != nil {
+		t.Fatalf("a missing CLI config file should not be an error, got: %s", err)
+	}
+	if token != "" {
+		t.Errorf("expected no token when no CLI config file exists, got %q", token)
+	}
+}
@@ -0,0 +1,77 @@
+// Package registry implements the client side of the Terraform module
+// registry protocol: host discovery, version listing, and download source
+// resolution for modules addressed as "<host>/<namespace>/<name>/<provider>".
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// discoveryPath is the well-known location, relative to the registry host,
+// where the service discovery document is published.
+const discoveryPath = "/.well-known/terraform.json"
+
+// Discovery describes the subset of a host's service discovery document
+// that is relevant to module registry operations.
+type Discovery struct {
+	// ModulesV1 is the base path, relative to the host, at which the
+	// modules.v1 protocol is served. It always ends in a slash.
+	ModulesV1 string `json:"modules.v1"`
+}
+
+// discover fetches and parses the service discovery document for the given
+// hostname. It does not consult or populate the client's cache; callers
+// should go through Client.discoveryForHost instead.
+func (c *Client) discover(host string) (*Discovery, error) {
+	url := fmt.Sprintf("https://%s%s", host, discoveryPath)
+
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %s", host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover %s: %s", host, resp.Status)
+	}
+
+	var disco Discovery
+	if err := json.NewDecoder(resp.Body).Decode(&disco); err != nil {
+		return nil, fmt.Errorf("invalid service discovery document for %s: %s", host, err)
+	}
+	if disco.ModulesV1 == "" {
+		return nil, fmt.Errorf("host %s does not provide a module registry", host)
+	}
+	// The discovery protocol requires modules.v1 to end in a slash, but
+	// doesn't require us to reject a document that forgot it, so we
+	// normalize here rather than produce a malformed URL everywhere we
+	// build one from it below.
+	if !strings.HasSuffix(disco.ModulesV1, "/") {
+		disco.ModulesV1 += "/"
+	}
+
+	return &disco, nil
+}
+
+// discoveryForHost returns the cached discovery document for host, fetching
+// and caching it first if necessary. Discovery is cached for the lifetime
+// of the Client so that a config with many modules on the same registry
+// host only pays the discovery round-trip once.
+func (c *Client) discoveryForHost(host string) (*Discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if disco, ok := c.discoCache[host]; ok {
+		return disco, nil
+	}
+
+	disco, err := c.discover(host)
+	if err != nil {
+		return nil, err
+	}
+	c.discoCache[host] = disco
+	return disco, nil
+}
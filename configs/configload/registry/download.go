@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Location returns the go-getter source string that should be used to fetch
+// the given module version's package, as reported by the registry's
+// X-Terraform-Get header on its download endpoint.
+//
+// The returned string may itself be a relative or indirect go-getter
+// address (for example, pointing at a separate object storage host) and
+// should be resolved relative to the registry host before use.
+func (c *Client) Location(addr ModuleAddr, v *version.Version) (string, error) {
+	disco, err := c.discoveryForHost(addr.Host)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf(
+		"https://%s%s%s/%s/%s/%s/download",
+		addr.Host, disco.ModulesV1, addr.Namespace, addr.Name, addr.Provider, v.String(),
+	)
+
+	req, err := c.newAuthenticatedRequest("GET", url, addr.Host)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request module download location: %s", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		// Both are allowed by the protocol: a 204 with the header is the
+		// common case, but some registries respond 200 with a body too.
+	default:
+		return "", fmt.Errorf("failed to request module download location: %s", resp.Status)
+	}
+
+	get := resp.Header.Get("X-Terraform-Get")
+	if get == "" {
+		return "", fmt.Errorf("registry response for %s is missing the X-Terraform-Get header", addr)
+	}
+
+	return get, nil
+}
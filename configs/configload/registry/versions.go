@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// moduleVersionsResponse is the shape of the response body from the
+// "<namespace>/<name>/<provider>/versions" endpoint of the modules.v1
+// protocol.
+type moduleVersionsResponse struct {
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+// Versions returns the published version strings for the given module, in
+// the order the registry reported them. Results are cached per ModuleAddr
+// for the lifetime of the Client.
+func (c *Client) Versions(addr ModuleAddr) ([]string, error) {
+	c.mu.Lock()
+	if vs, ok := c.verCache[addr]; ok {
+		c.mu.Unlock()
+		return vs, nil
+	}
+	c.mu.Unlock()
+
+	disco, err := c.discoveryForHost(addr.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(
+		"https://%s%s%s/%s/%s/versions",
+		addr.Host, disco.ModulesV1, addr.Namespace, addr.Name, addr.Provider,
+	)
+
+	req, err := c.newAuthenticatedRequest("GET", url, addr.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request module versions: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to request module versions: %s", resp.Status)
+	}
+
+	var body moduleVersionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid module versions response: %s", err)
+	}
+	if len(body.Modules) == 0 {
+		return nil, fmt.Errorf("registry returned no module versions for %s", addr)
+	}
+
+	var versions []string
+	for _, v := range body.Modules[0].Versions {
+		versions = append(versions, v.Version)
+	}
+
+	c.mu.Lock()
+	c.verCache[addr] = versions
+	c.mu.Unlock()
+
+	return versions, nil
+}
+
+// NewestMatchingVersion returns the highest published version of the given
+// module that satisfies constraint, or an error if none do.
+func (c *Client) NewestMatchingVersion(addr ModuleAddr, constraint version.Constraints) (*version.Version, error) {
+	available, err := c.Versions(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *version.Version
+	for _, raw := range available {
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			// Registries are expected to only publish valid semver, but
+			// we skip anything we can't parse rather than fail outright.
+			continue
+		}
+		if !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no version of module %s matches constraint %s", addr, constraint)
+	}
+	return best, nil
+}
+
+func (addr ModuleAddr) String() string {
+	return strings.Join([]string{addr.Host, addr.Namespace, addr.Name, addr.Provider}, "/")
+}
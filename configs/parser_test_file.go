@@ -0,0 +1,165 @@
+package configs
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hcl"
+)
+
+// LoadTestFile reads the file at the given path and parses it as a test
+// suite file (.tftest.hcl or .tftest.json), returning the resulting
+// TestFile representation.
+//
+// If the returned diagnostics contains errors then the result may be
+// incomplete, but LoadTestFile always returns a non-nil *TestFile so that
+// callers can accumulate diagnostics across multiple files before deciding
+// whether to proceed.
+func (p *Parser) LoadTestFile(path string) (*TestFile, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	body, fDiags := p.loadTestFileBody(path)
+	diags = append(diags, fDiags...)
+	if body == nil {
+		return &TestFile{}, diags
+	}
+
+	tf := &TestFile{
+		Variables: map[string]hcl.Expression{},
+		Providers: map[string]*TestFileProvider{},
+	}
+
+	content, contentDiags := body.Content(testFileSchema)
+	diags = append(diags, contentDiags...)
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "variables":
+			attrs, attrDiags := block.Body.JustAttributes()
+			diags = append(diags, attrDiags...)
+			for name, attr := range attrs {
+				tf.Variables[name] = attr.Expr
+			}
+
+		case "provider":
+			provider := &TestFileProvider{
+				Name:      block.Labels[0],
+				Config:    block.Body,
+				DeclRange: block.DefRange,
+			}
+			key := provider.Name
+			if attrs, _ := block.Body.JustAttributes(); attrs["alias"] != nil {
+				if aliasVal, aliasDiags := attrs["alias"].Expr.Value(nil); !aliasDiags.HasErrors() {
+					provider.Alias = aliasVal.AsString()
+					key = provider.Name + "." + provider.Alias
+				}
+			}
+			tf.Providers[key] = provider
+
+		case "run":
+			run, runDiags := decodeTestRunBlock(block)
+			diags = append(diags, runDiags...)
+			if run != nil {
+				tf.Runs = append(tf.Runs, run)
+			}
+		}
+	}
+
+	return tf, diags
+}
+
+// loadTestFileBody parses the raw HCL or JSON body of a test file, choosing
+// the syntax based on the file's extension in the same way LoadConfigFile
+// does for ordinary configuration files.
+func (p *Parser) loadTestFileBody(path string) (hcl.Body, hcl.Diagnostics) {
+	if strings.HasSuffix(path, ".json") {
+		f, diags := p.hclParser.ParseJSONFile(path)
+		if f == nil {
+			return nil, diags
+		}
+		return f.Body, diags
+	}
+
+	f, diags := p.hclParser.ParseHCLFile(path)
+	if f == nil {
+		return nil, diags
+	}
+	return f.Body, diags
+}
+
+func decodeTestRunBlock(block *hcl.Block) (*TestRun, hcl.Diagnostics) {
+	run := &TestRun{
+		Name:      block.Labels[0],
+		DeclRange: block.DefRange,
+	}
+
+	content, diags := block.Body.Content(testRunSchema)
+
+	for _, innerBlock := range content.Blocks {
+		switch innerBlock.Type {
+		case "module":
+			module, moduleDiags := decodeTestRunModuleCall(innerBlock)
+			diags = append(diags, moduleDiags...)
+			run.Module = module
+
+		case "variables":
+			attrs, attrDiags := innerBlock.Body.JustAttributes()
+			diags = append(diags, attrDiags...)
+			if run.Variables == nil {
+				run.Variables = map[string]hcl.Expression{}
+			}
+			for name, attr := range attrs {
+				run.Variables[name] = attr.Expr
+			}
+
+		case "assert":
+			assertion, assertDiags := decodeTestAssertionBlock(innerBlock)
+			diags = append(diags, assertDiags...)
+			if assertion != nil {
+				run.Assertions = append(run.Assertions, assertion)
+			}
+		}
+	}
+
+	return run, diags
+}
+
+func decodeTestRunModuleCall(block *hcl.Block) (*TestRunModuleCall, hcl.Diagnostics) {
+	var content struct {
+		Source hcl.Expression `hcl:"source,attr"`
+	}
+	diags := gohcl.DecodeBody(block.Body, nil, &content)
+	return &TestRunModuleCall{
+		Source:    content.Source,
+		DeclRange: block.DefRange,
+	}, diags
+}
+
+func decodeTestAssertionBlock(block *hcl.Block) (*TestAssertion, hcl.Diagnostics) {
+	var content struct {
+		Condition    hcl.Expression `hcl:"condition,attr"`
+		ErrorMessage hcl.Expression `hcl:"error_message,attr"`
+	}
+	diags := gohcl.DecodeBody(block.Body, nil, &content)
+	return &TestAssertion{
+		Condition:    content.Condition,
+		ErrorMessage: content.ErrorMessage,
+		DeclRange:    block.DefRange,
+	}, diags
+}
+
+var testFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variables"},
+		{Type: "provider", LabelNames: []string{"type"}},
+		{Type: "run", LabelNames: []string{"name"}},
+	},
+}
+
+var testRunSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "module"},
+		{Type: "variables"},
+		{Type: "assert", LabelNames: []string{"name"}},
+	},
+}
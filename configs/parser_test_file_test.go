@@ -0,0 +1,72 @@
+package configs
+
+import (
+	"testing"
+)
+
+func TestParserLoadTestFile(t *testing.T) {
+	parser := NewParser(nil)
+	tf, diags := parser.LoadTestFile("test-fixtures/valid.tftest.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected errors: %s", diags)
+	}
+
+	if got, want := len(tf.Variables), 1; got != want {
+		t.Fatalf("wrong number of file-level variables: got %d, want %d", got, want)
+	}
+	if _, ok := tf.Variables["input"]; !ok {
+		t.Errorf("missing file-level variable %q", "input")
+	}
+
+	if got, want := len(tf.Providers), 1; got != want {
+		t.Fatalf("wrong number of providers: got %d, want %d", got, want)
+	}
+	provider, ok := tf.Providers["test.primary"]
+	if !ok {
+		t.Fatalf("missing provider %q", "test.primary")
+	}
+	if got, want := provider.Alias, "primary"; got != want {
+		t.Errorf("wrong provider alias: got %q, want %q", got, want)
+	}
+
+	if got, want := len(tf.Runs), 2; got != want {
+		t.Fatalf("wrong number of runs: got %d, want %d", got, want)
+	}
+
+	setup := tf.Runs[0]
+	if got, want := setup.Name, "setup"; got != want {
+		t.Errorf("wrong name for first run: got %q, want %q", got, want)
+	}
+	if setup.Module == nil {
+		t.Fatal("expected first run to have a module override")
+	}
+	sourceVal, sDiags := setup.Module.Source.Value(nil)
+	if sDiags.HasErrors() {
+		t.Fatalf("unexpected errors evaluating module source: %s", sDiags)
+	}
+	if got, want := sourceVal.AsString(), "./setup"; got != want {
+		t.Errorf("wrong module source: got %q, want %q", got, want)
+	}
+	if got, want := len(setup.Variables), 1; got != want {
+		t.Errorf("wrong number of run-level variables: got %d, want %d", got, want)
+	}
+	if got, want := len(setup.Assertions), 1; got != want {
+		t.Errorf("wrong number of assertions: got %d, want %d", got, want)
+	}
+
+	main := tf.Runs[1]
+	if main.Module != nil {
+		t.Error("expected second run to have no module override")
+	}
+}
+
+func TestParserLoadTestFile_missing(t *testing.T) {
+	parser := NewParser(nil)
+	tf, diags := parser.LoadTestFile("test-fixtures/does-not-exist.tftest.hcl")
+	if !diags.HasErrors() {
+		t.Fatal("expected errors for a missing test file")
+	}
+	if tf == nil {
+		t.Fatal("LoadTestFile should always return a non-nil *TestFile")
+	}
+}
@@ -0,0 +1,82 @@
+package configs
+
+import (
+	"github.com/hashicorp/hcl2/hcl"
+)
+
+// TestFile represents the contents of a single .tftest.hcl or .tftest.json
+// file within a test suite. It describes an ordered sequence of Run blocks
+// that exercise a module, along with any file-level variable defaults and
+// provider configurations that should be made available to every run.
+type TestFile struct {
+	// Variables are default values for the root module's input variables,
+	// applied to every run block in the file unless a run block overrides
+	// them with its own Variables.
+	Variables map[string]hcl.Expression
+
+	// Providers are provider configurations declared at the file level,
+	// keyed by provider type, or by "type.alias" for an aliased provider.
+	// TestRun has no field that refers to them.
+	Providers map[string]*TestFileProvider
+
+	// Runs is the ordered list of run blocks in the file. Order matters:
+	// runs are expected to execute sequentially, and later runs may refer
+	// to outputs produced by earlier ones.
+	Runs []*TestRun
+
+	DeclRange hcl.Range
+}
+
+// TestRun represents a single "run" block within a .tftest.hcl file: one
+// step of a test sequence that plans and/or applies a module with a
+// particular set of variable values and then checks the result with a set
+// of assertions.
+type TestRun struct {
+	Name string
+
+	// Module, if set, overrides the module under test for this run only,
+	// for example to exercise a submodule or a fixture module in place of
+	// the configuration under test. A nil Module means the run applies to
+	// the configuration being tested.
+	Module *TestRunModuleCall
+
+	// Variables are the values to assign to the root module's input
+	// variables for this run, overriding any same-named entries in the
+	// TestFile's Variables.
+	Variables map[string]hcl.Expression
+
+	// Assertions are the "assert" blocks declared in the run block, each
+	// of which is checked after the run's plan or apply completes.
+	Assertions []*TestAssertion
+
+	DeclRange hcl.Range
+}
+
+// TestRunModuleCall describes a "module" block nested inside a "run" block,
+// which allows a single test file to exercise more than one module source
+// across its run blocks.
+type TestRunModuleCall struct {
+	Source hcl.Expression
+
+	DeclRange hcl.Range
+}
+
+// TestFileProvider represents a "provider" block declared at the top level
+// of a test file, identified by its type and optional alias.
+type TestFileProvider struct {
+	Name   string
+	Alias  string
+	Config hcl.Body
+
+	DeclRange hcl.Range
+}
+
+// TestAssertion represents a single "assert" block within a run block: a
+// condition that must hold true once the run's plan or apply has completed,
+// along with an error message to present when it does not.
+type TestAssertion struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression
+
+	DeclRange hcl.Range
+}